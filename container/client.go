@@ -3,13 +3,14 @@ package container
 import (
 	"bytes"
 	"fmt"
-	"io/ioutil"
 	"strings"
 	"time"
 
+	wt "github.com/containrrr/watchtower/pkg/types"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/network"
 	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 )
@@ -21,7 +22,7 @@ const (
 // A Client is the interface through which watchtower interacts with the
 // Docker API.
 type Client interface {
-	ListContainers(Filter) ([]Container, error)
+	ListContainers(Filter, FilterSpec) ([]Container, error)
 	GetContainer(containerID string) (Container, error)
 	StopContainer(Container, time.Duration) error
 	StartContainer(Container) (string, error)
@@ -29,6 +30,14 @@ type Client interface {
 	IsContainerStale(Container) (bool, error)
 	RemoveImage(Container) error
 	ExecuteCommand(containerID string, command string) error
+	ExecuteCommandWithResult(containerID string, command string, stage string) (*wt.ExecResult, error)
+	// PullStats returns the stats recorded for the most recent pull of
+	// imageName, or nil if it hasn't been pulled by this client.
+	PullStats(imageName string) *wt.PullStats
+	// ExecResult returns the result recorded for the most recent exec
+	// hook run against containerID at the given lifecycle stage (see
+	// StagePreUpdate/StagePostUpdate), or nil if none was run.
+	ExecResult(containerID string, stage string) *wt.ExecResult
 }
 
 // NewClient returns a new Client instance which can be used to interact with
@@ -37,22 +46,48 @@ type Client interface {
 //  * DOCKER_HOST			the docker-engine host to send api requests to
 //  * DOCKER_TLS_VERIFY		whether to verify tls certificates
 //  * DOCKER_API_VERSION	the minimum docker api version to work with
-func NewClient(pullImages bool) Client {
-	cli, err := dockerclient.NewEnvClient()
+func NewClient(pullImages bool, warnOnly bool, digestCheckOnly bool) Client {
+	return NewClientWithAPI(nil, pullImages, warnOnly, digestCheckOnly)
+}
 
-	if err != nil {
-		log.Fatalf("Error instantiating Docker client: %s", err)
+// NewClientWithAPI returns a new Client instance backed by the given
+// ThinDockerAPI. If api is nil, it falls back to a real Docker SDK client
+// configured from the environment, just like NewClient. This indirection
+// exists mainly so tests can substitute mocks.FakeDockerAPI.
+func NewClientWithAPI(api ThinDockerAPI, pullImages bool, warnOnly bool, digestCheckOnly bool) Client {
+	if api == nil {
+		cli, err := dockerclient.NewEnvClient()
+		if err != nil {
+			log.Fatalf("Error instantiating Docker client: %s", err)
+		}
+		api = cli
 	}
 
-	return dockerClient{api: cli, pullImages: pullImages}
+	return dockerClient{api: api, pullImages: pullImages, warnOnly: warnOnly, digestCheckOnly: digestCheckOnly, state: newClientState()}
 }
 
+var _ Client = dockerClient{}
+
 type dockerClient struct {
-	api        *dockerclient.Client
-	pullImages bool
+	api             ThinDockerAPI
+	pullImages      bool
+	warnOnly        bool
+	digestCheckOnly bool
+	includeStopped  bool
+	// state is shared by every value-receiver copy of dockerClient, since
+	// all of its methods are called on copies rather than a pointer.
+	state *clientState
 }
 
-func (client dockerClient) ListContainers(fn Filter) ([]Container, error) {
+func (client dockerClient) PullStats(imageName string) *wt.PullStats {
+	return client.state.getPullStats(imageName)
+}
+
+func (client dockerClient) ExecResult(containerID string, stage string) *wt.ExecResult {
+	return client.state.getExecResult(containerID, stage)
+}
+
+func (client dockerClient) ListContainers(fn Filter, spec FilterSpec) ([]Container, error) {
 	cs := []Container{}
 	bg := context.Background()
 
@@ -60,7 +95,7 @@ func (client dockerClient) ListContainers(fn Filter) ([]Container, error) {
 
 	runningContainers, err := client.api.ContainerList(
 		bg,
-		types.ContainerListOptions{})
+		types.ContainerListOptions{All: client.includeStopped || spec.IncludeStopped, Filters: spec.Args()})
 
 	if err != nil {
 		return nil, err
@@ -196,6 +231,20 @@ func (client dockerClient) IsContainerStale(c Container) (bool, error) {
 	oldImageInfo := c.imageInfo
 	imageName := c.ImageName()
 
+	if client.digestCheckOnly {
+		stale, err := CompareDigest(c)
+		if err == nil && !stale {
+			// Registry digest matches what's already pulled locally: no
+			// need to touch the registry again or pull anything.
+			return false, nil
+		}
+		if err != nil {
+			log.Debugf("Digest check failed for %s, falling back to pull: %s", imageName, err)
+		}
+		// A stale digest still has to be followed by an actual pull below
+		// before client.api.ImageInspectWithRaw can see the new image ID.
+	}
+
 	if client.pullImages {
 		log.Debugf("Pulling %s for %s", imageName, c.Name())
 
@@ -213,13 +262,22 @@ func (client dockerClient) IsContainerStale(c Container) (bool, error) {
 
 		response, err := client.api.ImagePull(bg, imageName, opts)
 		if err != nil {
+			if client.warnOnly {
+				log.Warnf("Could not pull image %s, treating it as not stale: %s", imageName, err)
+				return false, nil
+			}
 			log.Debugf("Error pulling image %s, %s", imageName, err)
 			return false, err
 		}
-		defer response.Close()
 
-		// the pull request will be aborted prematurely unless the response is read
-		_, err = ioutil.ReadAll(response)
+		// the pull request will be aborted prematurely unless the response is read;
+		// reportPullProgress reads it to completion while dispatching layer
+		// progress to any registered PullProgress listeners.
+		stats, err := reportPullProgress(imageName, response)
+		if err != nil {
+			return false, err
+		}
+		client.state.setPullStats(imageName, stats)
 	}
 
 	newImageInfo, _, err := client.api.ImageInspectWithRaw(bg, imageName)
@@ -236,25 +294,43 @@ func (client dockerClient) IsContainerStale(c Container) (bool, error) {
 	return false, nil
 }
 
+// execTimeout bounds how long watchtower will wait on a pre/post-update
+// exec hook before giving up on it.
+const execTimeout = 30 * time.Second
+
 func (client dockerClient) ExecuteCommand(containerID string, command string) error {
-	bg := context.Background()
+	_, err := client.ExecuteCommandWithResult(containerID, command, "")
+	return err
+}
+
+// ExecuteCommandWithResult runs command inside containerID and returns a
+// structured ExecResult with stdout and stderr demultiplexed into separate
+// buffers (Tty is disabled so stdcopy.StdCopy can split the stream), along
+// with the exit code and how long the exec took. The exec is aborted if it
+// doesn't complete within execTimeout. If stage is StagePreUpdate or
+// StagePostUpdate, the result is also recorded on the client so it can
+// later be attached to that container's ContainerStatus; pass "" for ad
+// hoc execs that a report shouldn't carry.
+func (client dockerClient) ExecuteCommandWithResult(containerID string, command string, stage string) (*wt.ExecResult, error) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
 
-	// Create the exec
 	execConfig := types.ExecConfig{
-		Tty:          true,
+		Tty:          false,
 		AttachStderr: true,
 		AttachStdout: true,
 		Detach:       false,
 		Cmd:          []string{"sh", "-c", command},
 	}
 
-	exec, err := client.api.ContainerExecCreate(bg, containerID, execConfig)
+	exec, err := client.api.ContainerExecCreate(ctx, containerID, execConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	response, attachErr := client.api.ContainerExecAttach(bg, exec.ID, types.ExecConfig{
-		Tty:          true,
+	response, attachErr := client.api.ContainerExecAttach(ctx, exec.ID, types.ExecConfig{
+		Tty:          false,
 		AttachStderr: true,
 		AttachStdout: true,
 		Detach:       false,
@@ -264,41 +340,61 @@ func (client dockerClient) ExecuteCommand(containerID string, command string) er
 	}
 
 	// Run the exec
-	execStartCheck := types.ExecStartCheck{Detach: false, Tty: true}
-	err = client.api.ContainerExecStart(bg, exec.ID, execStartCheck)
-	if err != nil {
-		return err
+	execStartCheck := types.ExecStartCheck{Detach: false, Tty: false}
+	if err = client.api.ContainerExecStart(ctx, exec.ID, execStartCheck); err != nil {
+		return nil, err
 	}
 
-	var execOutput string
+	var stdout, stderr bytes.Buffer
 	if attachErr == nil {
-		defer response.Close()
-		var writer bytes.Buffer
-		written, err := writer.ReadFrom(response.Reader)
-		if err != nil {
-			log.Error(err)
-		} else if written > 0 {
-			execOutput = strings.TrimSpace(writer.String())
+		// response.Reader is a read on the hijacked connection and isn't
+		// itself bound to ctx, so a hung command would block here forever;
+		// run the demux on its own goroutine and give up on ctx.Done(),
+		// closing the connection to unblock the read.
+		copyDone := make(chan error, 1)
+		go func() {
+			_, copyErr := stdcopy.StdCopy(&stdout, &stderr, response.Reader)
+			copyDone <- copyErr
+		}()
+
+		select {
+		case copyErr := <-copyDone:
+			if copyErr != nil {
+				log.Error(copyErr)
+			}
+			response.Close()
+		case <-ctx.Done():
+			response.Close()
+			return nil, fmt.Errorf("exec of %q in %s timed out after %s", command, containerID, execTimeout)
 		}
 	}
 
 	// Inspect the exec to get the exit code and print a message if the
 	// exit code is not success.
-	execInspect, err := client.api.ContainerExecInspect(bg, exec.ID)
+	execInspect, err := client.api.ContainerExecInspect(ctx, exec.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if execInspect.ExitCode > 0 {
-		log.Errorf("Command exited with code %v.", execInspect.ExitCode)
-		log.Error(execOutput)
-	} else {
-		if len(execOutput) > 0 {
-			log.Infof("Command output:\n%v", execOutput)
-		}
+	result := &wt.ExecResult{
+		ExitCode: execInspect.ExitCode,
+		Stdout:   strings.TrimSpace(stdout.String()),
+		Stderr:   strings.TrimSpace(stderr.String()),
+		Duration: time.Since(start),
 	}
 
-	return nil
+	if result.ExitCode > 0 {
+		log.Errorf("Command exited with code %v.", result.ExitCode)
+		log.Error(result.Stderr)
+	} else if len(result.Stdout) > 0 {
+		log.Infof("Command output:\n%v", result.Stdout)
+	}
+
+	if stage != "" {
+		client.state.setExecResult(containerID, stage, result)
+	}
+
+	return result, nil
 }
 
 func (client dockerClient) RemoveImage(c Container) error {
@@ -0,0 +1,248 @@
+package container
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	log "github.com/sirupsen/logrus"
+)
+
+// registryHTTPTimeout bounds the HEAD/token requests CompareDigest makes
+// against the registry, so a slow or unreachable registry can't hang a
+// poll cycle.
+const registryHTTPTimeout = 10 * time.Second
+
+// CompareDigest fetches the registry's manifest digest for the container's
+// image and compares it against the digests recorded for the locally
+// pulled image, without pulling the image itself. It returns true if the
+// remote digest is not found among the local RepoDigests, which signals
+// that a pull is required to pick up the new image.
+//
+// If the registry doesn't serve a manifest digest (e.g. a plain HTTP
+// registry, or a 404 on HEAD) or the local image has no RepoDigests at
+// all (e.g. it was built locally), CompareDigest reports an error so the
+// caller can fall back to the regular pull-and-compare staleness check.
+func CompareDigest(c Container) (bool, error) {
+	if len(c.imageInfo.RepoDigests) == 0 {
+		return false, fmt.Errorf("no repo digests available for %s", c.ImageName())
+	}
+
+	normalizedRef, err := reference.ParseDockerRef(c.ImageName())
+	if err != nil {
+		return false, err
+	}
+
+	digestURL, err := registryManifestURL(normalizedRef)
+	if err != nil {
+		return false, err
+	}
+
+	auth, err := EncodedAuth(c.ImageName())
+	if err != nil {
+		return false, err
+	}
+
+	digest, err := fetchManifestDigest(digestURL, auth)
+	if err != nil {
+		return false, err
+	}
+
+	for _, repoDigest := range c.imageInfo.RepoDigests {
+		if strings.Contains(repoDigest, digest) {
+			log.Debugf("No new images found for %s", c.Name())
+			return false, nil
+		}
+	}
+
+	log.Infof("Found new digest %s for %s", digest, c.ImageName())
+	return true, nil
+}
+
+// registryManifestURL builds the distribution API URL used to fetch the
+// manifest for a normalized image reference, e.g.
+// https://registry-1.docker.io/v2/library/nginx/manifests/latest
+func registryManifestURL(ref reference.Named) (string, error) {
+	tagged, ok := ref.(reference.NamedTagged)
+	tag := "latest"
+	if ok {
+		tag = tagged.Tag()
+	}
+
+	domain := reference.Domain(ref)
+	if domain == "docker.io" {
+		domain = "registry-1.docker.io"
+	}
+
+	return fmt.Sprintf("https://%s/v2/%s/manifests/%s", domain, reference.Path(ref), tag), nil
+}
+
+// fetchManifestDigest performs a HEAD request against the registry's
+// manifest endpoint and returns the Docker-Content-Digest header. Most
+// registries, including Docker Hub, reply to the anonymous HEAD with 401
+// and a Www-Authenticate bearer challenge; on a 401, negotiateBearerToken
+// follows that challenge to the token endpoint and the HEAD is retried
+// with the resulting bearer token.
+func fetchManifestDigest(manifestURL string, encodedAuth string) (string, error) {
+	client := &http.Client{Timeout: registryHTTPTimeout}
+
+	req, err := http.NewRequest(http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+
+	response, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusUnauthorized {
+		token, err := negotiateBearerToken(client, response.Header.Get("Www-Authenticate"), encodedAuth)
+		if err != nil {
+			return "", err
+		}
+
+		req, err = http.NewRequest(http.MethodHead, manifestURL, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		response, err = client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+	}
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(response.Body)
+		return "", fmt.Errorf("registry responded with status %s: %s", response.Status, string(body))
+	}
+
+	digest := response.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("no digest returned for %s", manifestURL)
+	}
+
+	return digest, nil
+}
+
+// negotiateBearerToken implements the registry token auth flow described by
+// https://docs.docker.com/registry/spec/auth/token/: it parses the
+// Www-Authenticate challenge from a 401 response for realm/service/scope,
+// then requests a token from the realm, using the registry credentials
+// carried in encodedAuth (the X-Registry-Auth base64 JSON EncodedAuth
+// already produces) as Basic auth if present, or anonymously otherwise.
+func negotiateBearerToken(client *http.Client, challenge string, encodedAuth string) (string, error) {
+	params := parseBearerChallenge(challenge)
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry did not present a bearer challenge: %q", challenge)
+	}
+
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", err
+	}
+
+	query := tokenURL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		query.Set("scope", scope)
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username, password, ok := decodeBasicCredentials(encodedAuth); ok {
+		req.SetBasicAuth(username, password)
+	}
+
+	response, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(response.Body)
+		return "", fmt.Errorf("token endpoint responded with status %s: %s", response.Status, string(body))
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+
+	if tokenResponse.Token != "" {
+		return tokenResponse.Token, nil
+	}
+	if tokenResponse.AccessToken != "" {
+		return tokenResponse.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("token endpoint returned no usable token")
+}
+
+// parseBearerChallenge parses a `Bearer realm="...",service="...",scope="..."`
+// Www-Authenticate header into its key/value parameters.
+func parseBearerChallenge(header string) map[string]string {
+	params := map[string]string{}
+	header = strings.TrimSpace(strings.TrimPrefix(header, "Bearer"))
+
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+
+	return params
+}
+
+// decodeBasicCredentials extracts the username/password from the base64
+// JSON auth config EncodedAuth returns (Docker's X-Registry-Auth format),
+// which is not the same thing as a base64-encoded "user:pass" Basic
+// credential. It reports ok=false when encodedAuth is empty or carries no
+// username, in which case the token should be requested anonymously.
+func decodeBasicCredentials(encodedAuth string) (username string, password string, ok bool) {
+	if encodedAuth == "" {
+		return "", "", false
+	}
+
+	decoded, err := base64.URLEncoding.DecodeString(encodedAuth)
+	if err != nil {
+		decoded, err = base64.StdEncoding.DecodeString(encodedAuth)
+		if err != nil {
+			return "", "", false
+		}
+	}
+
+	var authConfig struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal(decoded, &authConfig); err != nil || authConfig.Username == "" {
+		return "", "", false
+	}
+
+	return authConfig.Username, authConfig.Password, true
+}
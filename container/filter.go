@@ -0,0 +1,58 @@
+package container
+
+import (
+	"regexp"
+
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Filter is a function that decides whether a given container should be
+// included in the result of ListContainers. It is always applied in-process
+// after the daemon has returned its (optionally already filtered) list of
+// containers, e.g. to exclude watchtower's own container.
+type Filter func(Container) bool
+
+// FilterSpec declares the filters that can be pushed down to the Docker
+// daemon via types.ContainerListOptions, instead of being evaluated by
+// inspecting every container in Go. Any zero-valued field is left out of
+// the request.
+type FilterSpec struct {
+	// Labels is a set of "key=value" or "key" label selectors; containers
+	// must match all of them.
+	Labels []string
+	// NameRegex, if set, is matched against container names server-side
+	// (Docker matches it as an unanchored regular expression).
+	NameRegex *regexp.Regexp
+	// Statuses restricts the result to containers in one of these states,
+	// e.g. "running", "paused", "exited".
+	Statuses []string
+	// IncludeStopped controls whether non-running containers are requested
+	// at all; it maps to types.ContainerListOptions.All.
+	IncludeStopped bool
+}
+
+// Args converts the FilterSpec into the filters.Args the Docker API
+// expects for ContainerList.
+func (spec FilterSpec) Args() filters.Args {
+	args := filters.NewArgs()
+
+	for _, label := range spec.Labels {
+		args.Add("label", label)
+	}
+
+	if spec.NameRegex != nil {
+		args.Add("name", spec.NameRegex.String())
+	}
+
+	for _, status := range spec.Statuses {
+		args.Add("status", status)
+	}
+
+	return args
+}
+
+// NoServerFilter is the zero-value FilterSpec: no server-side
+// label/name/status narrowing, and IncludeStopped left false, so only
+// running containers are requested unless the client's own IncludeStopped
+// default (see ClientOptions) is set.
+var NoServerFilter = FilterSpec{}
@@ -0,0 +1,13 @@
+package mocks
+
+import (
+	"github.com/containrrr/watchtower/pkg/container"
+)
+
+// NewFakeClient returns a container.Client backed by api, so tests written
+// against the container.Client interface (ListContainers, StopContainer,
+// IsContainerStale, ...) can run without a real Docker daemon, using the
+// same FakeDockerAPI the lower-level tests use.
+func NewFakeClient(api *FakeDockerAPI, pullImages, warnOnly, digestCheckOnly bool) container.Client {
+	return container.NewClientWithAPI(api, pullImages, warnOnly, digestCheckOnly)
+}
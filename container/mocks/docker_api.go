@@ -0,0 +1,301 @@
+package mocks
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/containrrr/watchtower/pkg/container"
+	"github.com/docker/docker/api/types"
+	dockerContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"golang.org/x/net/context"
+)
+
+// compile-time assertion that FakeDockerAPI satisfies container.ThinDockerAPI
+var _ container.ThinDockerAPI = (*FakeDockerAPI)(nil)
+
+// FakeDockerAPI is an in-memory implementation of container.ThinDockerAPI,
+// letting tests exercise ListContainers, StopContainer, IsContainerStale
+// and friends without a real Docker daemon.
+type FakeDockerAPI struct {
+	mu         sync.Mutex
+	Containers map[string]types.ContainerJSON
+	Images     map[string]types.ImageInspect
+
+	// LastListOptions records the options passed to the most recent
+	// ContainerList call, so a test can assert on the filters.Args a
+	// FilterSpec was translated into.
+	LastListOptions types.ContainerListOptions
+
+	// ExecStdout, ExecStderr and ExecExitCode are what the next
+	// ContainerExecAttach/ContainerExecInspect call returns, letting a test
+	// drive ExecuteCommandWithResult through a real (if scripted) exec
+	// rather than a nil attach. Set via SetExecResult.
+	ExecStdout   string
+	ExecStderr   string
+	ExecExitCode int
+}
+
+// SetExecResult configures the stdout, stderr and exit code FakeDockerAPI
+// hands back for the next exec, as if a container hook had actually run.
+func (f *FakeDockerAPI) SetExecResult(stdout, stderr string, exitCode int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.ExecStdout = stdout
+	f.ExecStderr = stderr
+	f.ExecExitCode = exitCode
+}
+
+// NewFakeDockerAPI returns a FakeDockerAPI seeded with the given
+// containers and images, keyed by ID.
+func NewFakeDockerAPI(containers map[string]types.ContainerJSON, images map[string]types.ImageInspect) *FakeDockerAPI {
+	if containers == nil {
+		containers = map[string]types.ContainerJSON{}
+	}
+	if images == nil {
+		images = map[string]types.ImageInspect{}
+	}
+	return &FakeDockerAPI{Containers: containers, Images: images}
+}
+
+func (f *FakeDockerAPI) ContainerList(_ context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.LastListOptions = options
+
+	cs := []types.Container{}
+	for id, c := range f.Containers {
+		if !options.All && (c.State == nil || !c.State.Running) {
+			continue
+		}
+		if !matchesFilters(c, options.Filters) {
+			continue
+		}
+		cs = append(cs, types.Container{ID: id, Names: []string{c.Name}, Image: c.Image, Labels: c.Config.Labels})
+	}
+	return cs, nil
+}
+
+// matchesFilters reports whether container c satisfies the label, name and
+// status predicates in args, mirroring (a useful subset of) how the real
+// daemon narrows ContainerList server-side.
+func matchesFilters(c types.ContainerJSON, args filters.Args) bool {
+	for _, label := range args.Get("label") {
+		key, value := label, ""
+		if idx := strings.IndexByte(label, '='); idx >= 0 {
+			key, value = label[:idx], label[idx+1:]
+		}
+		if v, ok := c.Config.Labels[key]; !ok || (value != "" && v != value) {
+			return false
+		}
+	}
+
+	for _, name := range args.Get("name") {
+		re, err := regexp.Compile(name)
+		if err != nil || !re.MatchString(c.Name) {
+			return false
+		}
+	}
+
+	if statuses := args.Get("status"); len(statuses) > 0 {
+		state := "exited"
+		if c.State != nil && c.State.Running {
+			state = "running"
+		}
+		found := false
+		for _, status := range statuses {
+			if status == state {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f *FakeDockerAPI) ContainerInspect(_ context.Context, containerID string) (types.ContainerJSON, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.Containers[containerID]
+	if !ok {
+		return types.ContainerJSON{}, errNotFound(containerID)
+	}
+	return c, nil
+}
+
+func (f *FakeDockerAPI) ContainerKill(_ context.Context, containerID, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.Containers[containerID]
+	if !ok {
+		return errNotFound(containerID)
+	}
+	c.State.Running = false
+	f.Containers[containerID] = c
+	return nil
+}
+
+func (f *FakeDockerAPI) ContainerRemove(_ context.Context, containerID string, _ types.ContainerRemoveOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.Containers, containerID)
+	return nil
+}
+
+func (f *FakeDockerAPI) ContainerCreate(_ context.Context, config *dockerContainer.Config, hostConfig *dockerContainer.HostConfig, _ *network.NetworkingConfig, containerName string) (dockerContainer.ContainerCreateCreatedBody, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := "fake-" + containerName
+	f.Containers[id] = types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: id, Name: containerName, Image: config.Image, HostConfig: hostConfig},
+		Config:            config,
+	}
+	return dockerContainer.ContainerCreateCreatedBody{ID: id}, nil
+}
+
+func (f *FakeDockerAPI) ContainerStart(_ context.Context, containerID string, _ types.ContainerStartOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.Containers[containerID]
+	if !ok {
+		return errNotFound(containerID)
+	}
+	if c.State == nil {
+		c.State = &types.ContainerState{}
+	}
+	c.State.Running = true
+	f.Containers[containerID] = c
+	return nil
+}
+
+func (f *FakeDockerAPI) ContainerRename(_ context.Context, containerID, newContainerName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.Containers[containerID]
+	if !ok {
+		return errNotFound(containerID)
+	}
+	c.Name = newContainerName
+	f.Containers[containerID] = c
+	return nil
+}
+
+func (f *FakeDockerAPI) ImageInspectWithRaw(_ context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	img, ok := f.Images[imageID]
+	if !ok {
+		return types.ImageInspect{}, nil, errNotFound(imageID)
+	}
+	return img, nil, nil
+}
+
+func (f *FakeDockerAPI) ImagePull(_ context.Context, ref string, _ types.ImagePullOptions) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(`{"status":"Pull complete","id":"fake"}`)), nil
+}
+
+func (f *FakeDockerAPI) ImageRemove(_ context.Context, imageID string, _ types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.Images, imageID)
+	return []types.ImageDeleteResponseItem{{Deleted: imageID}}, nil
+}
+
+func (f *FakeDockerAPI) NetworkConnect(_ context.Context, _, _ string, _ *network.EndpointSettings) error {
+	return nil
+}
+
+func (f *FakeDockerAPI) NetworkDisconnect(_ context.Context, _, _ string, _ bool) error {
+	return nil
+}
+
+func (f *FakeDockerAPI) ContainerExecCreate(_ context.Context, _ string, _ types.ExecConfig) (types.IDResponse, error) {
+	return types.IDResponse{ID: "fake-exec"}, nil
+}
+
+// ContainerExecAttach returns the stdout/stderr configured via
+// SetExecResult, framed the way the Docker daemon multiplexes an attached
+// exec stream so that stdcopy.StdCopy can demultiplex it back apart; the
+// Conn is a no-op net.Conn so response.Close() is always safe to call.
+func (f *FakeDockerAPI) ContainerExecAttach(_ context.Context, _ string, _ types.ExecConfig) (types.HijackedResponse, error) {
+	f.mu.Lock()
+	stdout, stderr := f.ExecStdout, f.ExecStderr
+	f.mu.Unlock()
+
+	var framed bytes.Buffer
+	framed.Write(stdCopyFrame(stdoutStream, []byte(stdout)))
+	framed.Write(stdCopyFrame(stderrStream, []byte(stderr)))
+
+	return types.HijackedResponse{Conn: noopConn{}, Reader: bufio.NewReader(&framed)}, nil
+}
+
+func (f *FakeDockerAPI) ContainerExecStart(_ context.Context, _ string, _ types.ExecStartCheck) error {
+	return nil
+}
+
+func (f *FakeDockerAPI) ContainerExecInspect(_ context.Context, _ string) (types.ContainerExecInspect, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return types.ContainerExecInspect{ExitCode: f.ExecExitCode}, nil
+}
+
+// stdoutStream and stderrStream are the stream-type bytes stdcopy.StdCopy
+// expects as the first byte of each frame header.
+const (
+	stdoutStream byte = 1
+	stderrStream byte = 2
+)
+
+// stdCopyFrame wraps payload in the 8-byte header (stream type + big-endian
+// length) stdcopy.StdCopy expects to precede each chunk of an attached exec
+// stream, so FakeDockerAPI's output round-trips through the real demuxer.
+func stdCopyFrame(stream byte, payload []byte) []byte {
+	frame := make([]byte, 8+len(payload))
+	frame[0] = stream
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(payload)))
+	copy(frame[8:], payload)
+	return frame
+}
+
+// noopConn is a net.Conn that does nothing, standing in for the hijacked
+// connection a real ContainerExecAttach would return; FakeDockerAPI has no
+// socket to hijack, but types.HijackedResponse.Close() always calls
+// Conn.Close(), so a nil Conn would panic.
+type noopConn struct{ net.Conn }
+
+func (noopConn) Close() error { return nil }
+
+func errNotFound(id string) error {
+	return &notFoundError{id}
+}
+
+type notFoundError struct {
+	id string
+}
+
+func (e *notFoundError) Error() string {
+	return "no such container or image: " + e.id
+}
@@ -0,0 +1,65 @@
+package container
+
+import wt "github.com/containrrr/watchtower/pkg/types"
+
+// HostScan pairs the containers discovered on one host with any error
+// encountered while connecting to or listing it, so a caller aggregating
+// several hosts into a single Report can still make progress if one host
+// is unreachable.
+type HostScan struct {
+	Host       string
+	Containers []Container
+	Err        error
+}
+
+// ScanHosts builds a Client for each entry in hostOptions and lists its
+// containers concurrently, applying fn and spec to each, then merges every
+// host's containers into a single session Report. This is the primitive a
+// multi-host CLI (repeated --host flags) fans out over: connecting to or
+// listing a given host never blocks the others, and a failure on one host
+// is reported in its own HostScan rather than aborting the scan or being
+// dropped from the per-host results; since ScanHosts only lists (it never
+// pulls or restarts anything), every container it found is reported as
+// StateScanned, with Updated/Failed/Skipped/Stale/Fresh left for whatever
+// later runs an update cycle over the result.
+func ScanHosts(hostOptions []ClientOptions, fn Filter, spec FilterSpec) ([]HostScan, wt.Report) {
+	results := make([]HostScan, len(hostOptions))
+	statusesPerHost := make(chan []wt.ContainerReport, len(hostOptions))
+	done := make(chan int, len(hostOptions))
+
+	for i, opts := range hostOptions {
+		go func(i int, opts ClientOptions) {
+			defer func() { done <- i }()
+
+			scan := HostScan{Host: opts.Host}
+			client, err := NewClientWithOptions(opts)
+			if err != nil {
+				scan.Err = err
+				results[i] = scan
+				statusesPerHost <- nil
+				return
+			}
+
+			scan.Containers, scan.Err = client.ListContainers(fn, spec)
+			results[i] = scan
+
+			statuses := make([]wt.ContainerReport, 0, len(scan.Containers))
+			for _, c := range scan.Containers {
+				statuses = append(statuses, NewContainerStatus(c, client, StateScanned, nil))
+			}
+			statusesPerHost <- statuses
+		}(i, opts)
+	}
+
+	for range hostOptions {
+		<-done
+	}
+	close(statusesPerHost)
+
+	var allStatuses []wt.ContainerReport
+	for statuses := range statusesPerHost {
+		allStatuses = append(allStatuses, statuses...)
+	}
+
+	return results, NewReport(allStatuses)
+}
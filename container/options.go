@@ -0,0 +1,83 @@
+package container
+
+import (
+	"path/filepath"
+
+	dockerclient "github.com/docker/docker/client"
+)
+
+// ClientOptions configures how NewClientWithOptions connects to a Docker
+// engine. It exists so watchtower can be embedded and pointed at a specific
+// (possibly remote, possibly TLS-secured) engine, rather than always
+// reading DOCKER_HOST/DOCKER_TLS_VERIFY/DOCKER_API_VERSION from the
+// environment.
+type ClientOptions struct {
+	// Host is the docker-engine host to send API requests to, e.g.
+	// "tcp://remote-engine:2376". Empty uses the SDK's default (reads
+	// DOCKER_HOST, falling back to the local socket).
+	Host string
+	// TLSCertPath, if set, is a directory containing ca.pem, cert.pem and
+	// key.pem used to secure the connection to Host.
+	TLSCertPath string
+	// TLSVerify controls whether the server's certificate is verified
+	// against the CA in TLSCertPath. Ignored if TLSCertPath is empty.
+	TLSVerify bool
+	// APIVersion pins the API version used for requests, e.g. "1.40".
+	// Ignored if Negotiate is true.
+	APIVersion string
+	// Negotiate, if true, negotiates the highest mutually supported API
+	// version with the engine instead of using APIVersion.
+	Negotiate bool
+	// IncludeStopped is this client's default for FilterSpec.IncludeStopped:
+	// ListContainers includes stopped containers if either this or the
+	// FilterSpec passed to that particular call says to.
+	IncludeStopped bool
+	// PullImages mirrors dockerClient.pullImages.
+	PullImages bool
+	// WarnOnly mirrors dockerClient.warnOnly.
+	WarnOnly bool
+	// DigestCheckOnly mirrors dockerClient.digestCheckOnly.
+	DigestCheckOnly bool
+}
+
+// NewClientWithOptions returns a new Client instance connected according to
+// opts. Unlike NewClient, connection errors are returned rather than
+// causing a log.Fatalf, so callers can fan out to multiple hosts and
+// decide for themselves how to handle a host that's unreachable.
+func NewClientWithOptions(opts ClientOptions) (Client, error) {
+	clientOpts := []dockerclient.Opt{dockerclient.FromEnv}
+
+	if opts.Host != "" {
+		clientOpts = append(clientOpts, dockerclient.WithHost(opts.Host))
+	}
+
+	if opts.TLSCertPath != "" {
+		ca := filepath.Join(opts.TLSCertPath, "ca.pem")
+		cert := filepath.Join(opts.TLSCertPath, "cert.pem")
+		key := filepath.Join(opts.TLSCertPath, "key.pem")
+		if !opts.TLSVerify {
+			ca = ""
+		}
+		clientOpts = append(clientOpts, dockerclient.WithTLSClientConfig(ca, cert, key))
+	}
+
+	if opts.Negotiate {
+		clientOpts = append(clientOpts, dockerclient.WithAPIVersionNegotiation())
+	} else if opts.APIVersion != "" {
+		clientOpts = append(clientOpts, dockerclient.WithVersion(opts.APIVersion))
+	}
+
+	cli, err := dockerclient.NewClientWithOpts(clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return dockerClient{
+		api:             cli,
+		pullImages:      opts.PullImages,
+		warnOnly:        opts.WarnOnly,
+		digestCheckOnly: opts.DigestCheckOnly,
+		includeStopped:  opts.IncludeStopped,
+		state:           newClientState(),
+	}, nil
+}
@@ -0,0 +1,118 @@
+package container
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	wt "github.com/containrrr/watchtower/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// PullProgress is implemented by anything that wants to be kept informed of
+// the progress of an image pull, such as a notifier that reports per-layer
+// download progress to Slack/email/MSTeams.
+type PullProgress interface {
+	// OnLayerProgress is called whenever Docker reports progress for an
+	// individual image layer. total is 0 when the registry did not report
+	// a size for the layer (e.g. "Already exists" / "Pull complete" events).
+	OnLayerProgress(imageName, layerID string, current, total int64)
+	// OnPullComplete is called once the pull has finished, successfully or
+	// not.
+	OnPullComplete(imageName string, err error)
+}
+
+// pullProgressListeners holds the listeners registered via
+// RegisterPullProgressListener. It is deliberately package-level since
+// pulls are initiated from dockerClient methods that operate on value
+// receivers. pullProgressListenersMu guards it, since several Clients (e.g.
+// one per host) may call IsContainerStale (and therefore
+// reportPullProgress) concurrently from multiple goroutines.
+var (
+	pullProgressListenersMu sync.RWMutex
+	pullProgressListeners   []PullProgress
+)
+
+// RegisterPullProgressListener adds a listener that will be notified of
+// layer-level progress and completion for every subsequent image pull.
+func RegisterPullProgressListener(listener PullProgress) {
+	pullProgressListenersMu.Lock()
+	defer pullProgressListenersMu.Unlock()
+	pullProgressListeners = append(pullProgressListeners, listener)
+}
+
+// snapshotPullProgressListeners returns the currently registered listeners,
+// so callers can range over them without holding the lock for the duration
+// of (potentially slow) listener callbacks.
+func snapshotPullProgressListeners() []PullProgress {
+	pullProgressListenersMu.RLock()
+	defer pullProgressListenersMu.RUnlock()
+	return append([]PullProgress(nil), pullProgressListeners...)
+}
+
+// pullEvent mirrors the subset of the line-delimited JSON objects Docker
+// writes to the image pull response stream that watchtower cares about.
+type pullEvent struct {
+	Status         string `json:"status"`
+	ID             string `json:"id"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+}
+
+// reportPullProgress decodes the line-delimited JSON stream returned by
+// ImagePull, dispatching layer progress events to any registered
+// PullProgress listeners, and returns summary stats for the pull once the
+// stream is drained. The pull request is aborted prematurely unless the
+// response is read to completion, so this also serves the purpose the
+// previous ioutil.ReadAll call did.
+func reportPullProgress(imageName string, body io.ReadCloser) (*wt.PullStats, error) {
+	defer body.Close()
+	start := time.Now()
+
+	layerTotals := map[string]int64{}
+	decoder := json.NewDecoder(bufio.NewReader(body))
+
+	for {
+		var event pullEvent
+		err := decoder.Decode(&event)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			notifyPullComplete(imageName, err)
+			return nil, err
+		}
+
+		if event.ID == "" {
+			continue
+		}
+		if event.ProgressDetail.Total > 0 {
+			layerTotals[event.ID] = event.ProgressDetail.Total
+		}
+
+		for _, listener := range snapshotPullProgressListeners() {
+			listener.OnLayerProgress(imageName, event.ID, event.ProgressDetail.Current, event.ProgressDetail.Total)
+		}
+	}
+
+	var totalBytes int64
+	for _, total := range layerTotals {
+		totalBytes += total
+	}
+
+	stats := &wt.PullStats{Layers: len(layerTotals), Bytes: totalBytes, Duration: time.Since(start)}
+
+	notifyPullComplete(imageName, nil)
+	log.Debugf("Pulled %s (%d layers, %d bytes)", imageName, stats.Layers, stats.Bytes)
+	return stats, nil
+}
+
+func notifyPullComplete(imageName string, err error) {
+	for _, listener := range snapshotPullProgressListeners() {
+		listener.OnPullComplete(imageName, err)
+	}
+}
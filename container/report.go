@@ -0,0 +1,63 @@
+package container
+
+import wt "github.com/containrrr/watchtower/pkg/types"
+
+// Container states a ContainerStatus can carry, used to bucket it into the
+// matching slot of a session Report built by NewReport. StateScanned is not
+// itself a bucket below Scanned(); every status is counted there regardless
+// of its more specific state.
+const (
+	StateScanned = "scanned"
+	StateUpdated = "updated"
+	StateFailed  = "failed"
+	StateSkipped = "skipped"
+	StateStale   = "stale"
+	StateFresh   = "fresh"
+)
+
+// sessionReport is the concrete wt.Report built up over a scan/update
+// session: every ContainerReport it was given is counted in Scanned, and
+// additionally bucketed by its State() into Updated/Failed/Skipped/
+// Stale/Fresh.
+type sessionReport struct {
+	scanned []wt.ContainerReport
+	updated []wt.ContainerReport
+	failed  []wt.ContainerReport
+	skipped []wt.ContainerReport
+	stale   []wt.ContainerReport
+	fresh   []wt.ContainerReport
+}
+
+var _ wt.Report = (*sessionReport)(nil)
+
+// NewReport builds a single session Report out of statuses, e.g. the
+// ContainerStatus entries collected while scanning one or more hosts, so
+// callers get back one Report regardless of how many hosts contributed to
+// it.
+func NewReport(statuses []wt.ContainerReport) wt.Report {
+	report := &sessionReport{scanned: statuses}
+
+	for _, status := range statuses {
+		switch status.State() {
+		case StateUpdated:
+			report.updated = append(report.updated, status)
+		case StateFailed:
+			report.failed = append(report.failed, status)
+		case StateSkipped:
+			report.skipped = append(report.skipped, status)
+		case StateStale:
+			report.stale = append(report.stale, status)
+		case StateFresh:
+			report.fresh = append(report.fresh, status)
+		}
+	}
+
+	return report
+}
+
+func (r *sessionReport) Scanned() []wt.ContainerReport { return r.scanned }
+func (r *sessionReport) Updated() []wt.ContainerReport { return r.updated }
+func (r *sessionReport) Failed() []wt.ContainerReport  { return r.failed }
+func (r *sessionReport) Skipped() []wt.ContainerReport { return r.skipped }
+func (r *sessionReport) Stale() []wt.ContainerReport   { return r.stale }
+func (r *sessionReport) Fresh() []wt.ContainerReport   { return r.fresh }
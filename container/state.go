@@ -0,0 +1,54 @@
+package container
+
+import (
+	"sync"
+
+	wt "github.com/containrrr/watchtower/pkg/types"
+)
+
+// clientState holds the per-client data dockerClient accumulates as a side
+// effect of pulling images and running exec hooks: the last PullStats seen
+// for an image, and the last ExecResult seen for a container at a given
+// lifecycle stage. It's referenced from dockerClient through a pointer so
+// that every value-receiver copy of dockerClient (all of its methods use a
+// value receiver) shares the same underlying storage, and is guarded by a
+// mutex since those copies may be used from several goroutines at once.
+type clientState struct {
+	mu          sync.Mutex
+	pullStats   map[string]*wt.PullStats
+	execResults map[string]map[string]*wt.ExecResult
+}
+
+func newClientState() *clientState {
+	return &clientState{
+		pullStats:   map[string]*wt.PullStats{},
+		execResults: map[string]map[string]*wt.ExecResult{},
+	}
+}
+
+func (s *clientState) setPullStats(imageName string, stats *wt.PullStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pullStats[imageName] = stats
+}
+
+func (s *clientState) getPullStats(imageName string) *wt.PullStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pullStats[imageName]
+}
+
+func (s *clientState) setExecResult(containerID, stage string, result *wt.ExecResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.execResults[containerID] == nil {
+		s.execResults[containerID] = map[string]*wt.ExecResult{}
+	}
+	s.execResults[containerID][stage] = result
+}
+
+func (s *clientState) getExecResult(containerID, stage string) *wt.ExecResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.execResults[containerID][stage]
+}
@@ -0,0 +1,70 @@
+package container
+
+import wt "github.com/containrrr/watchtower/pkg/types"
+
+// Lifecycle stages an ExecResult can be recorded against, matching the
+// pre/post-update hooks ContainerReport exposes.
+const (
+	StagePreUpdate  = "pre-update"
+	StagePostUpdate = "post-update"
+)
+
+// ContainerStatus is the concrete wt.ContainerReport implementation
+// dockerClient-based callers build up while scanning/updating a
+// container, so that a session's Report can carry identity, state,
+// PullStats and pre/post-update ExecResult data together.
+type ContainerStatus struct {
+	id         string
+	name       string
+	oldImageID string
+	newImageID string
+	imageName  string
+	err        string
+	state      string
+	pullStats  *wt.PullStats
+	preExec    *wt.ExecResult
+	postExec   *wt.ExecResult
+}
+
+var _ wt.ContainerReport = (*ContainerStatus)(nil)
+
+// NewContainerStatus builds a ContainerStatus for c in the given state,
+// filling in PullStats and pre/post-update ExecResult from whatever client
+// recorded for this container/image during the current update cycle.
+func NewContainerStatus(c Container, client Client, state string, reportErr error) *ContainerStatus {
+	status := &ContainerStatus{
+		id:        c.ID(),
+		name:      c.Name(),
+		imageName: c.ImageName(),
+		state:     state,
+		pullStats: client.PullStats(c.ImageName()),
+		preExec:   client.ExecResult(c.ID(), StagePreUpdate),
+		postExec:  client.ExecResult(c.ID(), StagePostUpdate),
+	}
+
+	if c.imageInfo != nil {
+		status.oldImageID = c.ImageID()
+	}
+	if reportErr != nil {
+		status.err = reportErr.Error()
+	}
+
+	return status
+}
+
+// SetNewImageID records the ID of the image the container was restarted
+// with, once StartContainer has returned.
+func (s *ContainerStatus) SetNewImageID(id string) {
+	s.newImageID = id
+}
+
+func (s *ContainerStatus) ID() string                     { return s.id }
+func (s *ContainerStatus) Name() string                   { return s.name }
+func (s *ContainerStatus) OldImageID() string             { return s.oldImageID }
+func (s *ContainerStatus) NewImageID() string             { return s.newImageID }
+func (s *ContainerStatus) ImageName() string              { return s.imageName }
+func (s *ContainerStatus) Error() string                  { return s.err }
+func (s *ContainerStatus) State() string                  { return s.state }
+func (s *ContainerStatus) PullStats() *wt.PullStats       { return s.pullStats }
+func (s *ContainerStatus) PreUpdateExec() *wt.ExecResult  { return s.preExec }
+func (s *ContainerStatus) PostUpdateExec() *wt.ExecResult { return s.postExec }
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // Report contains reports for all the containers processed during a session
 type Report interface {
 	Scanned() []ContainerReport
@@ -19,4 +21,29 @@ type ContainerReport interface {
 	ImageName() string
 	Error() string
 	State() string
+	PullStats() *PullStats
+	PreUpdateExec() *ExecResult
+	PostUpdateExec() *ExecResult
+}
+
+// PullStats summarizes the data transferred while pulling a new image for a
+// container, for display in notifier messages. It is nil when no pull was
+// made for the container's image (e.g. it was skipped, failed before a
+// pull was attempted, or a digest check found it fresh); a pull that found
+// nothing new still leaves it set.
+type PullStats struct {
+	Layers   int
+	Bytes    int64
+	Duration time.Duration
+}
+
+// ExecResult holds the outcome of a pre/post-update lifecycle hook run
+// inside a container, with stdout and stderr kept separate so notifier
+// templates can render them distinctly. It is nil when no hook was
+// configured for that stage.
+type ExecResult struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
 }